@@ -0,0 +1,108 @@
+package strcursor
+
+import (
+	"io"
+	"unicode/utf8"
+)
+
+// ByteCursor is a cursor for consumers that mostly want to test and
+// consume raw bytes (e.g. ASCII punctuation, keywords) and only
+// occasionally need a full rune, avoiding the cost of a UTF-8 decode on
+// every peek. It wraps a Source, sharing the same buffer and
+// line/column bookkeeping as RuneCursor; see NewByteCursorFromSource to
+// pair one with a RuneCursor over the same input.
+type ByteCursor struct {
+	*Source
+}
+
+// NewByteCursor creates a cursor that deals primarily in bytes.
+func NewByteCursor(in io.Reader, nn ...int) *ByteCursor {
+	return NewByteCursorFromSource(NewSource(in, nn...))
+}
+
+// NewByteCursorFromSource creates a ByteCursor over an already-existing
+// Source, so it can share a buffer with a RuneCursor constructed from
+// the same Source.
+func NewByteCursorFromSource(src *Source) *ByteCursor {
+	return &ByteCursor{Source: src}
+}
+
+// ConsumeByte returns the current byte and consumes it. ok is false
+// once the underlying source is exhausted.
+func (c *ByteCursor) ConsumeByte() (b byte, ok bool) {
+	return c.advanceByte()
+}
+
+// PeekByte returns the current byte without consuming it.
+func (c *ByteCursor) PeekByte() (byte, bool) {
+	return c.PeekByteN(1)
+}
+
+// PeekByteN returns the n-th byte without consuming it.
+func (c *ByteCursor) PeekByteN(n int) (byte, bool) {
+	if n <= 0 {
+		n = 1
+	}
+
+	var b byte
+	ok := c.withRewind(false, func() bool {
+		for i := 0; i < n; i++ {
+			var ok2 bool
+			if b, ok2 = c.advanceByte(); !ok2 {
+				return false
+			}
+		}
+		return true
+	})
+	return b, ok
+}
+
+// HasPrefixBytes returns true if the next len(p) bytes equal p. It does
+// NOT consume upon a match.
+func (c *ByteCursor) HasPrefixBytes(p []byte) bool {
+	return c.hasPrefixBytes(p, false)
+}
+
+// ConsumeBytes consumes the next len(p) bytes if, and only if, they
+// equal p.
+func (c *ByteCursor) ConsumeBytes(p []byte) bool {
+	return c.hasPrefixBytes(p, true)
+}
+
+func (c *ByteCursor) hasPrefixBytes(p []byte, consume bool) bool {
+	return c.withRewind(consume, func() bool {
+		for _, want := range p {
+			got, ok := c.advanceByte()
+			if !ok || got != want {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// Rune decodes and consumes a full UTF-8 rune starting at the current
+// position, for callers that need to escape out of byte mode when they
+// see a leading byte >= utf8.RuneSelf.
+func (c *ByteCursor) Rune() rune {
+	r, ok := c.advanceRune()
+	if !ok {
+		return utf8.RuneError
+	}
+	return r
+}
+
+// PeekRune decodes the rune at the current position without consuming
+// it.
+func (c *ByteCursor) PeekRune() rune {
+	var r rune
+	ok := c.withRewind(false, func() bool {
+		var ok2 bool
+		r, ok2 = c.advanceRune()
+		return ok2
+	})
+	if !ok {
+		return utf8.RuneError
+	}
+	return r
+}
@@ -0,0 +1,97 @@
+package strcursor
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestByteCursorPeekConsumeByte(t *testing.T) {
+	c := NewByteCursor(strings.NewReader("abc"))
+
+	if got, ok := c.PeekByte(); !ok || got != 'a' {
+		t.Fatalf("PeekByte() = %q, %v; want 'a', true", got, ok)
+	}
+	if got, ok := c.PeekByteN(2); !ok || got != 'b' {
+		t.Fatalf("PeekByteN(2) = %q, %v; want 'b', true", got, ok)
+	}
+	// Peeking must not consume.
+	if got, ok := c.ConsumeByte(); !ok || got != 'a' {
+		t.Fatalf("ConsumeByte() = %q, %v; want 'a', true", got, ok)
+	}
+	if got, ok := c.ConsumeByte(); !ok || got != 'b' {
+		t.Fatalf("ConsumeByte() = %q, %v; want 'b', true", got, ok)
+	}
+	if got, ok := c.ConsumeByte(); !ok || got != 'c' {
+		t.Fatalf("ConsumeByte() = %q, %v; want 'c', true", got, ok)
+	}
+	if _, ok := c.ConsumeByte(); ok {
+		t.Fatalf("ConsumeByte() at EOF: ok = true, want false")
+	}
+}
+
+func TestByteCursorHasPrefixConsumeBytes(t *testing.T) {
+	c := NewByteCursor(strings.NewReader("foobar"))
+
+	if c.HasPrefixBytes([]byte("foz")) {
+		t.Fatalf("HasPrefixBytes(%q) = true, want false", "foz")
+	}
+	if !c.HasPrefixBytes([]byte("foo")) {
+		t.Fatalf("HasPrefixBytes(%q) = false, want true", "foo")
+	}
+	if got, _ := c.PeekByte(); got != 'f' {
+		t.Fatalf("PeekByte() after HasPrefixBytes = %q, want 'f'", got)
+	}
+
+	if !c.ConsumeBytes([]byte("foo")) {
+		t.Fatalf("ConsumeBytes(%q) = false, want true", "foo")
+	}
+	if got, _ := c.PeekByte(); got != 'b' {
+		t.Fatalf("PeekByte() after ConsumeBytes = %q, want 'b'", got)
+	}
+}
+
+func TestByteCursorRunePeekRune(t *testing.T) {
+	c := NewByteCursor(strings.NewReader("a日b"))
+
+	if got, ok := c.ConsumeByte(); !ok || got != 'a' {
+		t.Fatalf("ConsumeByte() = %q, %v; want 'a', true", got, ok)
+	}
+	if got := c.PeekRune(); got != '日' {
+		t.Fatalf("PeekRune() = %q, want '日'", got)
+	}
+	if got := c.Rune(); got != '日' {
+		t.Fatalf("Rune() = %q, want '日'", got)
+	}
+	if got, ok := c.ConsumeByte(); !ok || got != 'b' {
+		t.Fatalf("ConsumeByte() = %q, %v; want 'b', true", got, ok)
+	}
+	if c.Rune() != utf8.RuneError {
+		t.Fatalf("Rune() at EOF should be utf8.RuneError")
+	}
+}
+
+func TestByteAndRuneCursorShareASource(t *testing.T) {
+	src := NewSource(strings.NewReader("ab日cd"))
+	bc := NewByteCursorFromSource(src)
+	rc := NewRuneCursorFromSource(src)
+
+	if got, ok := bc.ConsumeByte(); !ok || got != 'a' {
+		t.Fatalf("ConsumeByte() = %q, %v; want 'a', true", got, ok)
+	}
+	if got := rc.Cur(); got != 'b' {
+		t.Fatalf("Cur() = %q, want 'b'", got)
+	}
+	if got := bc.Rune(); got != '日' {
+		t.Fatalf("Rune() = %q, want '日'", got)
+	}
+	if got, ok := bc.ConsumeByte(); !ok || got != 'c' {
+		t.Fatalf("ConsumeByte() = %q, %v; want 'c', true", got, ok)
+	}
+	if got := rc.Cur(); got != 'd' {
+		t.Fatalf("Cur() = %q, want 'd'", got)
+	}
+	if rc.LineNumber() != 1 {
+		t.Fatalf("LineNumber() = %d, want 1", rc.LineNumber())
+	}
+}
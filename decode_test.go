@@ -0,0 +1,102 @@
+package strcursor
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+// oneByteReader serves its input one byte at a time, to force a
+// multi-byte rune to straddle the boundary between two fill()s.
+type oneByteReader struct {
+	data []byte
+}
+
+func (r *oneByteReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, r.data[:1])
+	r.data = r.data[1:]
+	return n, nil
+}
+
+func TestRuneCursorRuneStraddlingBufferBoundary(t *testing.T) {
+	const want = "日本語"
+	c := NewRuneCursor(&oneByteReader{data: []byte(want)})
+
+	var got []rune
+	for !c.Done() {
+		got = append(got, c.Cur())
+	}
+	if string(got) != want {
+		t.Fatalf("got %q, want %q", string(got), want)
+	}
+}
+
+// emptyReadReader returns (0, nil) a fixed number of times before
+// producing any data, simulating a reader that occasionally stalls.
+type emptyReadReader struct {
+	stalls int
+	data   []byte
+}
+
+func (r *emptyReadReader) Read(p []byte) (int, error) {
+	if r.stalls > 0 {
+		r.stalls--
+		return 0, nil
+	}
+	if len(r.data) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestRuneCursorToleratesStalledReader(t *testing.T) {
+	c := NewRuneCursor(&emptyReadReader{stalls: 5, data: []byte("ok")})
+	if got := c.Cur(); got != 'o' {
+		t.Fatalf("Cur() = %q, want 'o'", got)
+	}
+}
+
+func TestRuneCursorErrNoProgressOnStuckReader(t *testing.T) {
+	c := NewRuneCursor(&emptyReadReader{stalls: maxConsecutiveEmptyReads + 1, data: []byte("x")})
+	if !c.Done() {
+		t.Fatalf("Done() = false, want true for a reader stuck past the retry cap")
+	}
+
+	c = NewRuneCursor(&emptyReadReader{stalls: maxConsecutiveEmptyReads + 1, data: []byte("x")})
+	if err := c.fill(); err != io.ErrNoProgress {
+		t.Fatalf("fill() = %v, want io.ErrNoProgress", err)
+	}
+}
+
+func TestRuneCursorErrorHandlerOnInvalidUTF8(t *testing.T) {
+	// \xff is never a valid UTF-8 lead byte.
+	c := NewRuneCursor(strings.NewReader("a\xffb"))
+
+	var errs []string
+	c.ErrorHandler = func(line, col int, msg string) {
+		errs = append(errs, msg)
+	}
+
+	var got []rune
+	for !c.Done() {
+		got = append(got, c.Cur())
+	}
+
+	if len(errs) != 1 {
+		t.Fatalf("got %d decode errors, want 1: %v", len(errs), errs)
+	}
+	want := []rune{'a', 0xFFFD, 'b'} // utf8.RuneError == '�'
+	if len(got) != len(want) {
+		t.Fatalf("got %q, want %q", string(got), string(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("rune %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package strcursor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRuneCursorMarkEmitDiscard(t *testing.T) {
+	c := NewRuneCursor(strings.NewReader("12+34"))
+
+	c.Mark()
+	c.Advance(2)
+	if got := c.Segment(); got != "12" {
+		t.Fatalf("Segment() = %q, want %q", got, "12")
+	}
+	if got := c.Emit(); got != "12" {
+		t.Fatalf("Emit() = %q, want %q", got, "12")
+	}
+	if got := c.Segment(); got != "" {
+		t.Fatalf("Segment() after Emit = %q, want \"\"", got)
+	}
+
+	c.Advance(1) // consume "+"
+	c.Mark()
+	c.Advance(2)
+	c.Discard()
+	if got := c.Segment(); got != "" {
+		t.Fatalf("Segment() after Discard = %q, want \"\"", got)
+	}
+}
+
+func TestRuneCursorMarkSurvivesRefill(t *testing.T) {
+	// A tiny buffer forces a refill while a mark is active, which must
+	// not lose the marked bytes.
+	c := NewRuneCursor(strings.NewReader("abcdefgh"), 2)
+
+	c.Mark()
+	c.Advance(6)
+	if got, want := c.Emit(), "abcdef"; got != want {
+		t.Fatalf("Emit() = %q, want %q", got, want)
+	}
+}
+
+func TestRuneCursorSegmentNoActiveMark(t *testing.T) {
+	c := NewRuneCursor(strings.NewReader("abc"))
+	if got := c.Segment(); got != "" {
+		t.Fatalf("Segment() with no Mark = %q, want \"\"", got)
+	}
+	if got := c.Emit(); got != "" {
+		t.Fatalf("Emit() with no Mark = %q, want \"\"", got)
+	}
+}
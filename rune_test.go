@@ -0,0 +1,99 @@
+package strcursor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRuneCursorCurPeekAdvance(t *testing.T) {
+	c := NewRuneCursor(strings.NewReader("hello"))
+
+	if got := c.Peek(); got != 'h' {
+		t.Fatalf("Peek() = %q, want 'h'", got)
+	}
+	if got := c.PeekN(2); got != 'e' {
+		t.Fatalf("PeekN(2) = %q, want 'e'", got)
+	}
+	// Peeking must not consume.
+	if got := c.Cur(); got != 'h' {
+		t.Fatalf("Cur() = %q, want 'h'", got)
+	}
+	if err := c.Advance(3); err != nil {
+		t.Fatalf("Advance(3) returned error: %v", err)
+	}
+	if got := c.Cur(); got != 'o' {
+		t.Fatalf("Cur() after Advance(3) = %q, want 'o'", got)
+	}
+	if !c.Done() {
+		t.Fatalf("Done() = false, want true at end of input")
+	}
+	if err := c.Advance(1); err == nil {
+		t.Fatalf("Advance(1) past EOF should have returned an error")
+	}
+}
+
+func TestRuneCursorSmallBufferGrowsAcrossRefills(t *testing.T) {
+	// A buffer far smaller than the input forces repeated fill()s and,
+	// with multi-byte runes, a slide across the refill boundary.
+	const input = "a日b本c語d"
+	c := NewRuneCursor(strings.NewReader(input), 2)
+
+	var got []rune
+	for !c.Done() {
+		got = append(got, c.Cur())
+	}
+
+	want := []rune(input)
+	if len(got) != len(want) {
+		t.Fatalf("got %d runes, want %d: %q", len(got), len(want), string(got))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("rune %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestRuneCursorLineColumn(t *testing.T) {
+	c := NewRuneCursor(strings.NewReader("ab\ncd"))
+
+	if c.LineNumber() != 1 || c.Column() != 1 {
+		t.Fatalf("initial position = %d:%d, want 1:1", c.LineNumber(), c.Column())
+	}
+	c.Advance(3) // consumes "ab\n"
+	if c.LineNumber() != 2 || c.Column() != 1 {
+		t.Fatalf("after newline = %d:%d, want 2:1", c.LineNumber(), c.Column())
+	}
+	c.Advance(1) // consumes "c"
+	if c.LineNumber() != 2 || c.Column() != 2 {
+		t.Fatalf("after 'c' = %d:%d, want 2:2", c.LineNumber(), c.Column())
+	}
+}
+
+func TestRuneCursorHasPrefixConsume(t *testing.T) {
+	c := NewRuneCursor(strings.NewReader("foobar"))
+
+	if c.HasPrefix("foz") {
+		t.Fatalf("HasPrefix(%q) = true, want false", "foz")
+	}
+	if !c.HasPrefix("foo") {
+		t.Fatalf("HasPrefix(%q) = false, want true", "foo")
+	}
+	// HasPrefix must not consume.
+	if got := c.Peek(); got != 'f' {
+		t.Fatalf("Peek() after HasPrefix = %q, want 'f'", got)
+	}
+
+	if !c.Consume("foo") {
+		t.Fatalf("Consume(%q) = false, want true", "foo")
+	}
+	if got := c.Peek(); got != 'b' {
+		t.Fatalf("Peek() after Consume = %q, want 'b'", got)
+	}
+	if c.Consume("xyz") {
+		t.Fatalf("Consume(%q) = true, want false", "xyz")
+	}
+	if got := c.Cur(); got != 'b' {
+		t.Fatalf("Cur() after failed Consume = %q, want 'b'", got)
+	}
+}
@@ -0,0 +1,425 @@
+package strcursor
+
+import (
+	"errors"
+	"io"
+	"unicode/utf8"
+)
+
+// minBufSize is the default size of the scratch buffer used to read from
+// the underlying io.Reader, not counting the trailing sentinel byte.
+const minBufSize = 512
+
+// maxConsecutiveEmptyReads is the number of consecutive (0, nil) reads
+// from the underlying io.Reader that fill tolerates before giving up and
+// reporting io.ErrNoProgress, mirroring bufio's handling of stalled
+// readers.
+const maxConsecutiveEmptyReads = 100
+
+// Source is the buffered byte window shared by RuneCursor and
+// ByteCursor: a single contiguous buffer (modelled after the buffer used
+// by Go's own syntax scanner), line/column tracking, and the
+// Mark/Emit/Discard and Checkpoint/Restore bookkeeping. Holding one
+// Source and wrapping it in both a RuneCursor and a ByteCursor lets a
+// parser switch between cheap byte tests and full rune decoding without
+// maintaining two buffers.
+type Source struct {
+	in io.Reader // input source
+
+	buf []byte // scratch buffer, read in from the io.Reader
+	b   int    // start of the segment being recorded, or -1 if none
+	r   int    // current read position within buf
+	chw int    // width, in bytes, of the last-read rune/byte
+	e   int    // end of valid content in buf; buf[e] holds the sentinel
+
+	nread  int // number of bytes consumed so far
+	lineno int // line number
+	column int // column number
+
+	// shadow state as of just before the last ReadRune/Cur/Advance(1)
+	// step, so UnreadRune can undo exactly that step by rewinding r by
+	// chw and restoring these
+	line0, col0, nread0 int
+	canUnread           bool
+
+	// pins holds buffer offsets, keyed by checkpoint id, that fill must
+	// not discard content before. unlike b (the Mark/Emit/Discard
+	// segment), a pin is released the moment its checkpoint is restored
+	nextPinID int
+	pins      map[int]int
+
+	// ErrorHandler, if set, is invoked for each recoverable decoding
+	// error encountered while scanning (currently: invalid UTF-8),
+	// letting a lexer collect multiple errors while continuing to scan.
+	// If unset, such runes simply come back as utf8.RuneError.
+	ErrorHandler func(line, col int, msg string)
+}
+
+// Checkpoint is a snapshot of a Source's position, taken with
+// Checkpoint and consumed by Restore, so a parser can speculatively
+// consume input and rewind to it on failure. It is independent of the
+// Mark/Emit/Discard segment: holding both at once is fine, but
+// restoring past a mark clears that mark, since the segment it bounded
+// no longer makes sense (see Restore).
+type Checkpoint struct {
+	owner  *Source // the Source that minted this Checkpoint
+	id     int
+	lineno int
+	column int
+	nread  int
+}
+
+// NewSource creates a Source reading from in. nn, if given, overrides
+// the initial scratch buffer size (in bytes).
+func NewSource(in io.Reader, nn ...int) *Source {
+	n := minBufSize
+	if len(nn) > 0 && nn[0] > 0 {
+		n = nn[0]
+	}
+
+	// +1 so there's always room for the utf8.RuneSelf sentinel at buf[e]
+	buf := make([]byte, n+1)
+	buf[0] = utf8.RuneSelf
+
+	return &Source{
+		in:     in,
+		buf:    buf,
+		b:      -1,
+		lineno: 1,
+		column: 1,
+	}
+}
+
+// fill slides whatever content is still needed (the active segment and
+// any outstanding checkpoints, or else just the unread tail) to the
+// front of buf, growing buf if there's no room left to read into, then
+// reads more bytes from the underlying io.Reader.
+func (s *Source) fill() error {
+	start := s.r
+	if s.b >= 0 && s.b < start {
+		start = s.b
+	}
+	for _, off := range s.pins {
+		if off < start {
+			start = off
+		}
+	}
+
+	if start > 0 {
+		n := copy(s.buf, s.buf[start:s.e])
+		s.e = n
+		s.r -= start
+		if s.b >= 0 {
+			s.b -= start
+		}
+		for id, off := range s.pins {
+			s.pins[id] = off - start
+		}
+	}
+
+	// grow the buffer if there's no room left to read into (the last byte
+	// is reserved for the sentinel)
+	if s.e+1 >= len(s.buf) {
+		buf := make([]byte, 2*len(s.buf))
+		copy(buf, s.buf[:s.e])
+		s.buf = buf
+	}
+
+	for i := 0; i < maxConsecutiveEmptyReads; i++ {
+		n, err := s.in.Read(s.buf[s.e : len(s.buf)-1])
+		if n == 0 && err == nil {
+			continue
+		}
+		s.e += n
+		s.buf[s.e] = utf8.RuneSelf
+		if n == 0 && err != nil {
+			return err
+		}
+		return nil
+	}
+	return io.ErrNoProgress
+}
+
+// advanceRune decodes the rune at the current read position, consuming
+// it and updating line/column/nread bookkeeping. ok is false once the
+// underlying io.Reader is genuinely exhausted (or errors out) with no
+// bytes left to decode.
+//
+// Before decoding, it uses utf8.FullRune to tell a rune that merely
+// straddles the current end of buf (keep reading) from one that is
+// already a complete, invalid encoding (report via ErrorHandler and
+// consume it as utf8.RuneError), rather than rejecting any trailing
+// bytes as a decode failure.
+func (s *Source) advanceRune() (r rune, ok bool) {
+	for s.r >= s.e || (s.buf[s.r] >= utf8.RuneSelf && !utf8.FullRune(s.buf[s.r:s.e])) {
+		if err := s.fill(); err != nil {
+			break
+		}
+	}
+	if s.r >= s.e {
+		return 0, false
+	}
+
+	var w int
+	if b := s.buf[s.r]; b < utf8.RuneSelf {
+		r, w = rune(b), 1
+	} else {
+		r, w = utf8.DecodeRune(s.buf[s.r:s.e])
+		if r == utf8.RuneError && w == 1 {
+			s.reportError(s.lineno, s.column, "invalid UTF-8 encoding")
+		}
+	}
+	s.advance(w, r == '\n')
+	return r, true
+}
+
+// advanceByte consumes a single byte at the current read position
+// without attempting any UTF-8 decoding. It is meant for callers that
+// already know (or don't care) that the byte is ASCII; use advanceRune
+// or Rune/PeekRune to cross a multi-byte sequence.
+func (s *Source) advanceByte() (b byte, ok bool) {
+	for s.r >= s.e {
+		if err := s.fill(); err != nil {
+			return 0, false
+		}
+	}
+	b = s.buf[s.r]
+	s.advance(1, b == '\n')
+	return b, true
+}
+
+// advance records a w-byte step (rune or raw byte) as consumed, updating
+// the unread-shadow, line/column, and total-read bookkeeping.
+func (s *Source) advance(w int, newline bool) {
+	s.line0, s.col0, s.nread0 = s.lineno, s.column, s.nread
+	s.r += w
+	s.chw = w
+	s.nread += w
+	if newline {
+		s.lineno++
+		s.column = 1
+	} else {
+		s.column++
+	}
+	s.canUnread = true
+}
+
+// reportError invokes ErrorHandler, if set.
+func (s *Source) reportError(line, col int, msg string) {
+	if s.ErrorHandler != nil {
+		s.ErrorHandler(line, col, msg)
+	}
+}
+
+// scalarState holds the Source fields that are independent of buffer
+// offsets (and thus unaffected by fill sliding the window around), used
+// to make lookahead operations transparent.
+type scalarState struct {
+	chw                   int
+	lineno, column, nread int
+	line0, col0, nread0   int
+	canUnread             bool
+}
+
+func (s *Source) saveScalars() scalarState {
+	return scalarState{
+		chw: s.chw, lineno: s.lineno, column: s.column, nread: s.nread,
+		line0: s.line0, col0: s.col0, nread0: s.nread0,
+		canUnread: s.canUnread,
+	}
+}
+
+func (s *Source) restoreScalars(st scalarState) {
+	s.chw, s.lineno, s.column, s.nread = st.chw, st.lineno, st.column, st.nread
+	s.line0, s.col0, s.nread0 = st.line0, st.col0, st.nread0
+	s.canUnread = st.canUnread
+}
+
+// withRewind pins the current position, runs fn, and - unless fn
+// succeeds and consume is true - rewinds the position and every scalar
+// back to where they were before fn ran. It composes with an already
+// active Mark: the caller's mark is left exactly as it was.
+func (s *Source) withRewind(consume bool, fn func() bool) bool {
+	hadMark := s.b >= 0
+	delta := 0
+	if hadMark {
+		delta = s.r - s.b
+	} else {
+		s.b = s.r
+	}
+	saved := s.saveScalars()
+
+	ok := fn()
+
+	if !ok || !consume {
+		s.r = s.b + delta
+		s.restoreScalars(saved)
+	}
+	if !hadMark {
+		s.b = -1
+	}
+	return ok
+}
+
+// Done returns true if there is no more input left.
+func (s *Source) Done() bool {
+	for s.r >= s.e {
+		if err := s.fill(); err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// LineNumber returns the current line number
+func (s *Source) LineNumber() int {
+	return s.lineno
+}
+
+// Column returns the current column number
+func (s *Source) Column() int {
+	return s.column
+}
+
+// Mark begins recording a segment starting at the current position.
+// Any previously active mark is replaced.
+func (s *Source) Mark() {
+	s.b = s.r
+}
+
+// Segment returns the bytes consumed since the last call to Mark. The
+// marked region is kept intact within the buffer window itself (the
+// window is grown rather than spilled to a side buffer when it would
+// otherwise be overwritten by a refill), so this is a single slice
+// conversion rather than a rune-by-rune rebuild. It returns "" if no
+// mark is active.
+func (s *Source) Segment() string {
+	if s.b < 0 {
+		return ""
+	}
+	return string(s.buf[s.b:s.r])
+}
+
+// Emit returns the segment recorded since the last call to Mark, and
+// clears the mark.
+func (s *Source) Emit() string {
+	seg := s.Segment()
+	s.b = -1
+	return seg
+}
+
+// Discard clears the active mark without returning its contents.
+func (s *Source) Discard() {
+	s.b = -1
+}
+
+// ReadRune implements io.RuneReader. It returns io.EOF once the
+// underlying source is exhausted.
+func (s *Source) ReadRune() (r rune, size int, err error) {
+	r, ok := s.advanceRune()
+	if !ok {
+		return 0, 0, io.EOF
+	}
+	return r, s.chw, nil
+}
+
+// UnreadRune implements io.RuneScanner. It undoes the effect of the last
+// ReadRune, Cur, or Advance(1) call, restoring the previous line/column.
+// Only a single step can be undone: after Advance(n) it undoes the last
+// of the n runes, and a second UnreadRune in a row returns an error, as
+// does one right after Restore. Lookahead (Peek, PeekN, HasPrefix) does
+// not affect it.
+//
+// If a Mark was set at the position just unread (e.g. Mark was called
+// right after the ReadRune being undone), it is cleared: see
+// clearStaleMark.
+func (s *Source) UnreadRune() error {
+	if !s.canUnread {
+		return errors.New("strcursor: UnreadRune: previous operation was not a successful ReadRune")
+	}
+	s.r -= s.chw
+	s.lineno, s.column, s.nread = s.line0, s.col0, s.nread0
+	s.chw = 0
+	s.canUnread = false
+	s.clearStaleMark()
+	return nil
+}
+
+// clearStaleMark clears the active mark if r has moved back before it,
+// which would otherwise leave b > r for a later Segment/Emit to panic
+// on.
+func (s *Source) clearStaleMark() {
+	if s.b > s.r {
+		s.b = -1
+	}
+}
+
+// Checkpoint snapshots the cursor's current position. The underlying
+// buffer pins the position so it survives refills no matter how far
+// ahead the cursor is subsequently advanced, letting a parser consume
+// arbitrarily far past it before deciding whether to Restore.
+//
+// A Checkpoint is independent of Mark/Emit/Discard: an active mark has
+// no bearing on whether a checkpoint can be restored, and vice versa.
+//
+// A Checkpoint pins its position in the buffer until it is released by
+// Restore or Commit; an abandoned Checkpoint prevents fill from ever
+// sliding past it, so the buffer grows to hold the rest of the stream
+// instead. Every Checkpoint must be paired with exactly one Restore or
+// Commit call.
+func (s *Source) Checkpoint() Checkpoint {
+	if s.pins == nil {
+		s.pins = make(map[int]int)
+	}
+	id := s.nextPinID
+	s.nextPinID++
+	s.pins[id] = s.r
+	return Checkpoint{owner: s, id: id, lineno: s.lineno, column: s.column, nread: s.nread}
+}
+
+// Restore rewinds the cursor to the position recorded by cp and
+// releases cp's pin on the buffer. A Checkpoint is consumed by Restore:
+// restoring it a second time, or restoring a Checkpoint taken on a
+// different Source, returns an error rather than silently reading from
+// a region that may since have been discarded (or, worse, another
+// Source's buffer entirely).
+//
+// If a Mark is active and its position now lies ahead of the restored
+// position, the mark is cleared: the segment it bounded no longer makes
+// sense, and leaving it would make a later Segment/Emit panic.
+func (s *Source) Restore(cp Checkpoint) error {
+	off, err := s.releaseCheckpoint(cp)
+	if err != nil {
+		return err
+	}
+	s.r = off
+	s.lineno, s.column, s.nread = cp.lineno, cp.column, cp.nread
+	s.chw = 0
+	s.canUnread = false
+	s.clearStaleMark()
+	return nil
+}
+
+// Commit releases cp's pin on the buffer without moving the cursor,
+// for the common case where the speculative parse cp guarded against
+// succeeded and input should simply keep flowing from the current
+// position. Like Restore, it returns an error if cp was already
+// released or belongs to a different Source.
+func (s *Source) Commit(cp Checkpoint) error {
+	_, err := s.releaseCheckpoint(cp)
+	return err
+}
+
+// releaseCheckpoint validates cp against s and deletes its pin, common
+// to both Restore and Commit.
+func (s *Source) releaseCheckpoint(cp Checkpoint) (int, error) {
+	if cp.owner != s {
+		return 0, errors.New("strcursor: checkpoint belongs to a different Source")
+	}
+	off, ok := s.pins[cp.id]
+	if !ok {
+		return 0, errors.New("strcursor: checkpoint already restored or discarded")
+	}
+	delete(s.pins, cp.id)
+	return off, nil
+}
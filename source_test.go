@@ -0,0 +1,142 @@
+package strcursor
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestRuneCursorReadRuneUnreadRune(t *testing.T) {
+	c := NewRuneCursor(strings.NewReader("ab"))
+
+	r, size, err := c.ReadRune()
+	if err != nil || r != 'a' || size != 1 {
+		t.Fatalf("ReadRune() = %q, %d, %v; want 'a', 1, nil", r, size, err)
+	}
+	if err := c.UnreadRune(); err != nil {
+		t.Fatalf("UnreadRune() returned error: %v", err)
+	}
+	// Unread twice in a row must fail: only one step can be undone.
+	if err := c.UnreadRune(); err == nil {
+		t.Fatalf("second UnreadRune() should have returned an error")
+	}
+
+	r, _, err = c.ReadRune()
+	if err != nil || r != 'a' {
+		t.Fatalf("ReadRune() after UnreadRune = %q, %v; want 'a', nil", r, err)
+	}
+
+	if _, _, err := c.ReadRune(); err != nil {
+		t.Fatalf("ReadRune() for 'b' returned error: %v", err)
+	}
+	if _, _, err := c.ReadRune(); err != io.EOF {
+		t.Fatalf("ReadRune() at EOF = %v, want io.EOF", err)
+	}
+}
+
+func TestSourceCheckpointRestore(t *testing.T) {
+	c := NewRuneCursor(strings.NewReader("abcdef"))
+
+	c.Advance(2) // past "ab"
+	cp := c.Checkpoint()
+	c.Advance(3) // past "cde", arbitrarily far ahead of the checkpoint
+
+	if err := c.Restore(cp); err != nil {
+		t.Fatalf("Restore() returned error: %v", err)
+	}
+	if got := c.Cur(); got != 'c' {
+		t.Fatalf("Cur() after Restore = %q, want 'c'", got)
+	}
+
+	// A Checkpoint is consumed by Restore: using it again must fail.
+	if err := c.Restore(cp); err == nil {
+		t.Fatalf("second Restore() with the same checkpoint should have returned an error")
+	}
+}
+
+func TestSourceRestoreRejectsCheckpointFromAnotherSource(t *testing.T) {
+	src1 := NewRuneCursor(strings.NewReader("0123456789"))
+	src2 := NewRuneCursor(strings.NewReader("abcdefghij"))
+
+	cp1 := src1.Checkpoint()
+
+	if err := src2.Restore(cp1); err == nil {
+		t.Fatalf("Restore() of another Source's checkpoint should have returned an error")
+	}
+	// src2 must be untouched by the rejected Restore.
+	if got := src2.Cur(); got != 'a' {
+		t.Fatalf("Cur() after rejected cross-Source Restore = %q, want 'a'", got)
+	}
+}
+
+func TestSourceRestoreClearsMarkAheadOfRestoredPosition(t *testing.T) {
+	c := NewRuneCursor(strings.NewReader("0123456789"))
+
+	cp := c.Checkpoint() // r=0
+	c.Advance(2)         // r=2
+	c.Mark()             // b=2
+	c.Advance(2)         // r=4
+
+	if err := c.Restore(cp); err != nil { // r=0, now behind the mark at b=2
+		t.Fatalf("Restore() returned error: %v", err)
+	}
+
+	// Must not panic with "slice bounds out of range [2:0]".
+	if got := c.Segment(); got != "" {
+		t.Fatalf("Segment() after Restore past a mark = %q, want \"\"", got)
+	}
+}
+
+func TestSourceUnreadRuneClearsMarkAheadOfRestoredPosition(t *testing.T) {
+	c := NewRuneCursor(strings.NewReader("ab"))
+
+	c.ReadRune()                           // r=1
+	c.Mark()                               // b=1
+	if err := c.UnreadRune(); err != nil { // r=0, now behind the mark at b=1
+		t.Fatalf("UnreadRune() returned error: %v", err)
+	}
+
+	// Must not panic with "slice bounds out of range [1:0]".
+	if got := c.Segment(); got != "" {
+		t.Fatalf("Segment() after UnreadRune past a mark = %q, want \"\"", got)
+	}
+}
+
+func TestSourceCommitReleasesCheckpointWithoutRewinding(t *testing.T) {
+	c := NewRuneCursor(strings.NewReader("abcdef"))
+
+	cp := c.Checkpoint()
+	c.Advance(3) // past "abc"
+
+	if err := c.Commit(cp); err != nil {
+		t.Fatalf("Commit() returned error: %v", err)
+	}
+	// Commit must not move the cursor.
+	if got := c.Cur(); got != 'd' {
+		t.Fatalf("Cur() after Commit = %q, want 'd'", got)
+	}
+
+	// A Checkpoint is consumed by Commit too: reusing it must fail.
+	if err := c.Restore(cp); err == nil {
+		t.Fatalf("Restore() of a committed checkpoint should have returned an error")
+	}
+}
+
+func TestSourceCheckpointSurvivesRefill(t *testing.T) {
+	// A tiny buffer forces repeated fill()s while the checkpoint is
+	// pinned; fill must keep the checkpointed byte around rather than
+	// sliding past it.
+	c := NewRuneCursor(strings.NewReader("abcdefgh"), 2)
+
+	cp := c.Checkpoint() // r=0, pinned at 'a'
+	for i := 0; i < 6; i++ {
+		c.Cur() // consume "abcdef", well past the tiny buffer's capacity
+	}
+
+	if err := c.Restore(cp); err != nil {
+		t.Fatalf("Restore() returned error: %v", err)
+	}
+	if got := c.Cur(); got != 'a' {
+		t.Fatalf("Cur() after Restore across refills = %q, want 'a'", got)
+	}
+}